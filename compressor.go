@@ -0,0 +1,66 @@
+package grpcweb
+
+import (
+	"io"
+	"sync"
+)
+
+// Compressor implements compression and decompression of gRPC message
+// payloads for a single Grpc-Encoding value.
+type Compressor interface {
+	// Name is the Grpc-Encoding value this Compressor handles, e.g. "gzip".
+	Name() string
+	// Compress returns a writer that compresses bytes written to it into w.
+	// Callers must Close the returned writer to flush any buffered data.
+	// grpcweb itself never originates compressed output -- it only ever
+	// decompresses a peer's frames to satisfy the other side's encoding
+	// constraints -- so Compress exists for the benefit of callers that want
+	// to produce gRPC-Web or gRPC frames of their own.
+	Compress(w io.Writer) io.WriteCloser
+	// Decompress returns a reader that decompresses r.
+	Decompress(r io.Reader) (io.Reader, error)
+}
+
+var (
+	compressorMu sync.RWMutex
+	compressors  = make(map[string]Compressor)
+)
+
+// RegisterCompressor registers c under c.Name(), making it available for
+// negotiation via the Grpc-Encoding and Grpc-Accept-Encoding headers.
+// RegisterCompressor is typically called from an init function; the registry
+// is guarded by a mutex, so it is safe for concurrent use.
+func RegisterCompressor(c Compressor) {
+	compressorMu.Lock()
+	defer compressorMu.Unlock()
+	compressors[c.Name()] = c
+}
+
+// compressorNamed returns the Compressor registered for name, if any.
+func compressorNamed(name string) (Compressor, bool) {
+	compressorMu.RLock()
+	defer compressorMu.RUnlock()
+	c, ok := compressors[name]
+	return c, ok
+}
+
+func init() {
+	RegisterCompressor(identityCompressor{})
+}
+
+// identityCompressor is the always-available, no-op "identity" encoding.
+type identityCompressor struct{}
+
+func (identityCompressor) Name() string { return "identity" }
+
+func (identityCompressor) Compress(w io.Writer) io.WriteCloser {
+	return nopWriteCloser{w}
+}
+
+func (identityCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	return r, nil
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
@@ -7,9 +7,11 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"google.golang.org/grpc/codes"
 )
@@ -29,23 +31,33 @@ const (
 	// Frame types
 	grpcDataFrame    byte = 0x00
 	grpcTrailerFrame byte = 0x80 // MSB is set
+
+	// grpcCompressedFlag is the LSB of a gRPC-Web (and gRPC) message header,
+	// set when the frame payload has been compressed.
+	grpcCompressedFlag byte = 0x01
 )
 
 // FrameReader translates a gRPC-Web request body into a standard gRPC request body.
 type FrameReader struct {
-	source io.Reader
-	buffer bytes.Buffer
+	source     io.Reader
+	compressor Compressor
+	buffer     bytes.Buffer
 }
 
-// NewFrameReader creates a new reader that translates gRPC-Web frames.
-func NewFrameReader(r io.Reader, isTextEncoded bool) io.Reader {
+// NewFrameReader creates a new reader that translates gRPC-Web frames. If
+// compressor is non-nil, any compressed frame is decompressed and forwarded
+// to the gRPC server as plaintext with the compressed flag cleared; callers
+// must advertise the corresponding outbound Grpc-Encoding as "identity"
+// since the server will never see a compressed payload.
+func NewFrameReader(r io.Reader, isTextEncoded bool, compressor Compressor) io.Reader {
 	var bodyReader io.Reader = r
 	if isTextEncoded {
 		bodyReader = base64.NewDecoder(base64.StdEncoding, bodyReader)
 	}
 
 	return &FrameReader{
-		source: bodyReader,
+		source:     bodyReader,
+		compressor: compressor,
 	}
 }
 
@@ -60,23 +72,43 @@ func (fr *FrameReader) Read(p []byte) (n int, err error) {
 		return 0, err
 	}
 
-	if frameHeader[0] != grpcDataFrame {
+	if frameHeader[0]&grpcTrailerFrame != 0 {
 		return 0, io.EOF
 	}
+	compressedFlag := frameHeader[0] & grpcCompressedFlag
 
 	length := binary.BigEndian.Uint32(frameHeader[1:])
+	// Copy into a buffer that only grows as bytes actually arrive, rather
+	// than pre-allocating the full attacker-declared length up front: length
+	// comes straight off the wire, and a handful of requests each claiming a
+	// multi-gigabyte frame (while sending none of it) would otherwise be
+	// enough to OOM the process.
+	var payloadBuf bytes.Buffer
+	if length > 0 {
+		if _, err := io.CopyN(&payloadBuf, fr.source, int64(length)); err != nil {
+			return 0, fmt.Errorf("error copying frame data: %w", err)
+		}
+	}
+	payload := payloadBuf.Bytes()
+
+	if compressedFlag != 0 && fr.compressor != nil {
+		decompressed, err := fr.compressor.Decompress(bytes.NewReader(payload))
+		if err != nil {
+			return 0, fmt.Errorf("error decompressing frame data: %w", err)
+		}
+		if payload, err = io.ReadAll(decompressed); err != nil {
+			return 0, fmt.Errorf("error decompressing frame data: %w", err)
+		}
+		length = uint32(len(payload))
+		compressedFlag = 0
+	}
 
 	// Translate to gRPC message header (1 byte compression flag + 4 bytes length)
 	grpcHeader := make([]byte, 5)
-	grpcHeader[0] = 0 // No compression
+	grpcHeader[0] = compressedFlag
 	binary.BigEndian.PutUint32(grpcHeader[1:], length)
 	fr.buffer.Write(grpcHeader)
-
-	if length > 0 {
-		if _, err := io.CopyN(&fr.buffer, fr.source, int64(length)); err != nil {
-			return 0, fmt.Errorf("error copying frame data: %w", err)
-		}
-	}
+	fr.buffer.Write(payload)
 
 	return fr.buffer.Read(p)
 }
@@ -92,10 +124,25 @@ type StreamingResponseWriter struct {
 	bodyWriter         io.Writer
 	flusher            http.Flusher
 	frameBuffer        *bytes.Buffer
+	announcedTrailers  []string
+	acceptedEncodings  map[string]bool
+	responseCompressor Compressor
+	decompressResponse bool
 }
 
 // NewStreamingResponseWriter creates a new gRPC-Web response writer.
-func NewStreamingResponseWriter(w http.ResponseWriter, isText bool) *StreamingResponseWriter {
+// acceptEncoding is the client's Grpc-Accept-Encoding header value; if the
+// backend responds with an encoding the client didn't list and a Compressor
+// is registered for that encoding, the response is transparently
+// decompressed before being forwarded; otherwise it is forwarded as is.
+func NewStreamingResponseWriter(w http.ResponseWriter, isText bool, acceptEncoding string) *StreamingResponseWriter {
+	acceptedEncodings := make(map[string]bool)
+	for _, enc := range strings.Split(acceptEncoding, ",") {
+		if enc = strings.TrimSpace(enc); enc != "" {
+			acceptedEncodings[enc] = true
+		}
+	}
+
 	srw := &StreamingResponseWriter{
 		w:                  w,
 		isTextResponse:     isText,
@@ -103,6 +150,7 @@ func NewStreamingResponseWriter(w http.ResponseWriter, isText bool) *StreamingRe
 		trailers:           make(http.Header),
 		capturedStatusCode: http.StatusOK,
 		frameBuffer:        &bytes.Buffer{},
+		acceptedEncodings:  acceptedEncodings,
 	}
 
 	var writer io.Writer = w
@@ -123,6 +171,13 @@ func (w *StreamingResponseWriter) Header() http.Header {
 	return w.headers
 }
 
+// Trailer returns the gRPC trailer set that will be emitted when Finish is
+// called. Callers may set Grpc-Status and Grpc-Message on it directly to
+// override the status derived from the response's HTTP status code.
+func (w *StreamingResponseWriter) Trailer() http.Header {
+	return w.trailers
+}
+
 // WriteHeader implements http.ResponseWriter.
 func (w *StreamingResponseWriter) WriteHeader(statusCode int) {
 	if w.headersWritten {
@@ -147,17 +202,30 @@ func (w *StreamingResponseWriter) Write(p []byte) (int, error) {
 			break
 		}
 
+		compressedFlag := grpcHeader[0] & grpcCompressedFlag
 		w.frameBuffer.Next(5)
+		payload := w.frameBuffer.Next(int(length))
+
+		if compressedFlag != 0 && w.decompressResponse {
+			decompressed, err := w.responseCompressor.Decompress(bytes.NewReader(payload))
+			if err != nil {
+				return 0, fmt.Errorf("error decompressing response frame: %w", err)
+			}
+			if payload, err = io.ReadAll(decompressed); err != nil {
+				return 0, fmt.Errorf("error decompressing response frame: %w", err)
+			}
+			compressedFlag = 0
+		}
 
 		grpcWebFrameHeader := make([]byte, 5)
-		grpcWebFrameHeader[0] = grpcDataFrame
-		binary.BigEndian.PutUint32(grpcWebFrameHeader[1:5], length)
+		grpcWebFrameHeader[0] = grpcDataFrame | compressedFlag
+		binary.BigEndian.PutUint32(grpcWebFrameHeader[1:5], uint32(len(payload)))
 
 		if _, err := w.bodyWriter.Write(grpcWebFrameHeader); err != nil {
 			return 0, err
 		}
 
-		if _, err := io.CopyN(w.bodyWriter, w.frameBuffer, int64(length)); err != nil {
+		if _, err := w.bodyWriter.Write(payload); err != nil {
 			return 0, err
 		}
 	}
@@ -181,6 +249,27 @@ func (w *StreamingResponseWriter) Finish() error {
 		w.writeHeaders()
 	}
 
+	// Headers announced via "Trailer:" may only acquire their value after
+	// the body has been written (e.g. a backend set via net/http/httputil
+	// populates them once the upstream response trailer arrives). Promote
+	// any such late-arriving values now.
+	for _, key := range w.announcedTrailers {
+		hoistTrailer(w.trailers, w.headers, key)
+	}
+
+	// A backend may also add trailers using the http.TrailerPrefix
+	// convention ("Trailer:"+key) instead of pre-announcing them via the
+	// "Trailer" header; grpc-go's handler_server does this for any
+	// trailer metadata set via grpc.SetTrailer beyond the well-known
+	// Grpc-Status/Grpc-Message/Grpc-Status-Details-Bin trio.
+	for key, val := range w.headers {
+		if !strings.HasPrefix(key, http.TrailerPrefix) {
+			continue
+		}
+		trailerKey := http.CanonicalHeaderKey(strings.TrimPrefix(key, http.TrailerPrefix))
+		w.trailers[trailerKey] = val
+	}
+
 	if w.trailers.Get("Grpc-Status") == "" {
 		grpcCode := httpStatusToGrpcCode(w.capturedStatusCode)
 		w.trailers.Set("Grpc-Status", strconv.Itoa(int(grpcCode)))
@@ -225,15 +314,25 @@ func (w *StreamingResponseWriter) writeHeaders() {
 		for _, trailer := range trailers {
 			for _, key := range strings.Split(trailer, ",") {
 				canonicalKey := http.CanonicalHeaderKey(strings.TrimSpace(key))
-				if val, ok := w.headers[canonicalKey]; ok {
-					w.trailers[canonicalKey] = val
-					delete(w.headers, canonicalKey)
-				}
+				w.announcedTrailers = append(w.announcedTrailers, canonicalKey)
+				hoistTrailer(w.trailers, w.headers, canonicalKey)
 			}
 		}
 	}
 	delete(w.headers, "Trailer")
 
+	exposeHeaders := "grpc-status, grpc-message"
+	if encoding := w.headers.Get("Grpc-Encoding"); encoding != "" && encoding != "identity" {
+		if !w.acceptedEncodings[encoding] {
+			if compressor, ok := compressorNamed(encoding); ok {
+				w.responseCompressor = compressor
+				w.decompressResponse = true
+				w.headers.Set("Grpc-Encoding", "identity")
+			}
+		}
+		exposeHeaders += ", grpc-encoding"
+	}
+
 	for k, v := range w.headers {
 		if k != "Content-Type" && k != "Content-Length" {
 			w.w.Header()[k] = v
@@ -245,7 +344,7 @@ func (w *StreamingResponseWriter) writeHeaders() {
 		finalContentType = ContentTypeGRPCWebTextProto
 	}
 	w.w.Header().Set("Content-Type", finalContentType)
-	w.w.Header().Add("Access-Control-Expose-Headers", "grpc-status, grpc-message")
+	w.w.Header().Add("Access-Control-Expose-Headers", exposeHeaders)
 
 	w.w.WriteHeader(http.StatusOK)
 }
@@ -262,6 +361,68 @@ func IsTextRequest(r *http.Request) bool {
 	return strings.HasSuffix(contentType, "-text") || strings.Contains(accept, ContentTypeGRPCWebText)
 }
 
+// DecodeTimeout parses the value of a Grpc-Timeout header into a
+// time.Duration, following the gRPC timeout encoding: a positive integer of
+// up to 8 ASCII digits followed by a single-character unit suffix (H, M, S,
+// m, u, or n).
+func DecodeTimeout(s string) (time.Duration, error) {
+	if len(s) < 2 {
+		return 0, fmt.Errorf("grpcweb: malformed Grpc-Timeout %q", s)
+	}
+
+	digits, unit := s[:len(s)-1], s[len(s)-1]
+	if len(digits) > 8 {
+		return 0, fmt.Errorf("grpcweb: malformed Grpc-Timeout %q: too many digits", s)
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return 0, fmt.Errorf("grpcweb: malformed Grpc-Timeout %q: not a positive integer", s)
+		}
+	}
+
+	value, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("grpcweb: malformed Grpc-Timeout %q", s)
+	}
+
+	var unitSize time.Duration
+	switch unit {
+	case 'H':
+		unitSize = time.Hour
+	case 'M':
+		unitSize = time.Minute
+	case 'S':
+		unitSize = time.Second
+	case 'm':
+		unitSize = time.Millisecond
+	case 'u':
+		unitSize = time.Microsecond
+	case 'n':
+		unitSize = time.Nanosecond
+	default:
+		return 0, fmt.Errorf("grpcweb: malformed Grpc-Timeout %q: unknown unit %q", s, string(unit))
+	}
+
+	if maxUnits := int64(math.MaxInt64) / int64(unitSize); value > maxUnits {
+		value = maxUnits
+	}
+
+	return time.Duration(value) * unitSize, nil
+}
+
+// hoistTrailer moves key's value from headers to trailers. Binary
+// ("-Bin" suffixed) metadata is forwarded verbatim: backends already emit it
+// as base64 per the gRPC "-bin" trailer convention, so re-encoding here
+// would double-encode it.
+func hoistTrailer(trailers, headers http.Header, key string) {
+	val, ok := headers[key]
+	if !ok {
+		return
+	}
+	trailers[key] = val
+	delete(headers, key)
+}
+
 func httpStatusToGrpcCode(httpStatusCode int) codes.Code {
 	switch httpStatusCode {
 	case http.StatusOK:
@@ -281,6 +442,41 @@ func httpStatusToGrpcCode(httpStatusCode int) codes.Code {
 	}
 }
 
+// GRPCCodeToHTTPStatus is the companion of httpStatusToGrpcCode: it maps a
+// gRPC status code back to an HTTP status. Handler itself never uses this
+// to pick the outer HTTP status of a gRPC-Web response, since gRPC-Web
+// clients expect a 200 OK carrying a trailers-only frame with the real
+// Grpc-Status regardless of the underlying failure; it is exported for
+// callers that bridge gRPC errors onto plain HTTP clients instead.
+func GRPCCodeToHTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Internal, codes.Unknown, codes.DataLoss:
+		return http.StatusInternalServerError
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 // flushingBase64Writer is a base64 encoder that supports http.Flusher.
 type flushingBase64Writer struct {
 	w       http.ResponseWriter
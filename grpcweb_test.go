@@ -1,18 +1,31 @@
 package grpcweb_test
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/base64"
 	"encoding/binary"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/http/httputil"
+	"net/textproto"
 	"net/url"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/xyalter/grpcweb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 // TestHandler_EndToEnd performs an integration test of the Handler, simulating a full
@@ -166,6 +179,601 @@ func TestHandler_EndToEnd(t *testing.T) {
 	}
 }
 
+// TestHandler_CompressedRequest_EndToEnd verifies that a gzip-compressed
+// gRPC-Web request frame reaches the backend as a plain, decompressed gRPC
+// frame advertising Grpc-Encoding: identity, rather than a still-compressed
+// payload the backend would have to understand gzip to read.
+func TestHandler_CompressedRequest_EndToEnd(t *testing.T) {
+	fakeGrpcServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Grpc-Encoding"); got != "identity" {
+			t.Errorf("Backend expected Grpc-Encoding 'identity', got %q", got)
+		}
+
+		reqBody, err := readGrpcFrame(r.Body)
+		if err != nil {
+			t.Errorf("Backend server failed to read gRPC request frame: %v", err)
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if string(reqBody) != "hello_world" {
+			t.Errorf("Backend server expected decompressed 'hello_world', got %q", string(reqBody))
+		}
+
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Add("Trailer", "Grpc-Status")
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	defer fakeGrpcServer.Close()
+
+	backendURL, err := url.Parse(fakeGrpcServer.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse backend server URL: %v", err)
+	}
+
+	grpcwebHandler := &grpcweb.Handler{
+		GRPCServer: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			httputil.NewSingleHostReverseProxy(backendURL).ServeHTTP(w, r)
+		}),
+	}
+
+	compressed := gzipCompress(t, []byte("hello_world"))
+	var body bytes.Buffer
+	header := []byte{0x01, 0, 0, 0, 0} // DATA frame, compressed flag set
+	binary.BigEndian.PutUint32(header[1:5], uint32(len(compressed)))
+	body.Write(header)
+	body.Write(compressed)
+
+	req := httptest.NewRequest("POST", "/service/method", &body)
+	req.Header.Set("Content-Type", "application/grpc-web+proto")
+	req.Header.Set("Grpc-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+
+	grpcwebHandler.ServeHTTP(rr, req)
+
+	if resp := rr.Result(); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status OK; got %d", resp.StatusCode)
+	}
+}
+
+// echoServiceDesc describes a minimal unary gRPC service used to drive
+// WrapGRPCServer against a real *grpc.Server: it echoes a wrapperspb.StringValue
+// back to the caller, or, if the request value is "fail", returns an error
+// status carrying an errdetails.BadRequest detail.
+var echoServiceDesc = grpc.ServiceDesc{
+	ServiceName: "grpcweb.test.Echo",
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Call",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				in := new(wrapperspb.StringValue)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				if in.GetValue() != "fail" {
+					return wrapperspb.String("echo:" + in.GetValue()), nil
+				}
+				st, err := status.New(codes.InvalidArgument, "bad value").WithDetails(
+					&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+						{Field: "value", Description: `must not be "fail"`},
+					}},
+				)
+				if err != nil {
+					return nil, err
+				}
+				return nil, st.Err()
+			},
+		},
+	},
+	Metadata: "grpcweb_test.go",
+}
+
+// TestWrapGRPCServer_EndToEnd drives a real *grpc.Server through WrapGRPCServer
+// with no reverse proxy in between, covering both a successful unary call and
+// one that fails with status details attached.
+func TestWrapGRPCServer_EndToEnd(t *testing.T) {
+	s := grpc.NewServer()
+	s.RegisterService(&echoServiceDesc, nil)
+	handler := grpcweb.WrapGRPCServer(s)
+
+	call := func(t *testing.T, value string) *http.Response {
+		t.Helper()
+		payload, err := proto.Marshal(wrapperspb.String(value))
+		if err != nil {
+			t.Fatalf("Failed to marshal request: %v", err)
+		}
+		reqBody, err := createGrpcWebRequestBody(payload, false)
+		if err != nil {
+			t.Fatalf("Failed to create request body: %v", err)
+		}
+		req := httptest.NewRequest("POST", "/grpcweb.test.Echo/Call", bytes.NewReader(reqBody))
+		req.Header.Set("Content-Type", "application/grpc-web+proto")
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+		return rr.Result()
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		resp := call(t, "hi")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status OK; got %d", resp.StatusCode)
+		}
+
+		frameType, data, err := readGrpcWebFrame(resp.Body)
+		if err != nil {
+			t.Fatalf("Failed to read response data frame: %v", err)
+		}
+		if frameType != 0x00 {
+			t.Fatalf("Expected first frame to be DATA (0x00), got %#x", frameType)
+		}
+		var out wrapperspb.StringValue
+		if err := proto.Unmarshal(data, &out); err != nil {
+			t.Fatalf("Failed to unmarshal response message: %v", err)
+		}
+		if out.GetValue() != "echo:hi" {
+			t.Errorf("Expected response value %q; got %q", "echo:hi", out.GetValue())
+		}
+
+		frameType, data, err = readGrpcWebFrame(resp.Body)
+		if err != nil {
+			t.Fatalf("Failed to read response trailer frame: %v", err)
+		}
+		if frameType != 0x80 {
+			t.Fatalf("Expected second frame to be TRAILER (0x80), got %#x", frameType)
+		}
+		trailers := strings.ToLower(strings.ReplaceAll(string(data), " ", ""))
+		if !strings.Contains(trailers, "grpc-status:0") {
+			t.Errorf("Expected trailers to contain 'grpc-status:0', got %q", data)
+		}
+	})
+
+	t.Run("ErrorWithDetails", func(t *testing.T) {
+		resp := call(t, "fail")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status OK; got %d", resp.StatusCode)
+		}
+
+		frameType, data, err := readGrpcWebFrame(resp.Body)
+		if err != nil {
+			t.Fatalf("Failed to read response trailer frame: %v", err)
+		}
+		if frameType != 0x80 {
+			t.Fatalf("Expected a TRAILER frame (0x80), got %#x", frameType)
+		}
+
+		mimeHeader, err := textproto.NewReader(bufio.NewReader(bytes.NewReader(append(data, "\r\n"...)))).ReadMIMEHeader()
+		if err != nil {
+			t.Fatalf("Failed to parse trailers: %v", err)
+		}
+		trailers := http.Header(mimeHeader)
+
+		if got := trailers.Get("Grpc-Status"); got != strconv.Itoa(int(codes.InvalidArgument)) {
+			t.Fatalf("Expected Grpc-Status %d; got %s", codes.InvalidArgument, got)
+		}
+
+		detailsBin := trailers.Get("Grpc-Status-Details-Bin")
+		if detailsBin == "" {
+			t.Fatalf("Expected a Grpc-Status-Details-Bin trailer")
+		}
+		raw, err := base64.RawStdEncoding.DecodeString(detailsBin)
+		if err != nil {
+			t.Fatalf("Failed to base64-decode Grpc-Status-Details-Bin: %v", err)
+		}
+		var decodedStatus spb.Status
+		if err := proto.Unmarshal(raw, &decodedStatus); err != nil {
+			t.Fatalf("Failed to unmarshal google.rpc.Status: %v", err)
+		}
+		if len(decodedStatus.GetDetails()) != 1 {
+			t.Fatalf("Expected exactly one status detail; got %d", len(decodedStatus.GetDetails()))
+		}
+		var violation errdetails.BadRequest
+		if err := decodedStatus.GetDetails()[0].UnmarshalTo(&violation); err != nil {
+			t.Fatalf("Failed to unmarshal BadRequest detail: %v", err)
+		}
+		if got := violation.GetFieldViolations()[0].GetField(); got != "value" {
+			t.Errorf("Expected field %q; got %q", "value", got)
+		}
+	})
+}
+
+// TestDecodeTimeout covers valid and malformed Grpc-Timeout header values.
+func TestDecodeTimeout(t *testing.T) {
+	validCases := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"5S", 5 * time.Second},
+		{"100m", 100 * time.Millisecond},
+		{"1H", time.Hour},
+		{"00000001n", time.Nanosecond},
+	}
+	for _, tc := range validCases {
+		got, err := grpcweb.DecodeTimeout(tc.in)
+		if err != nil {
+			t.Errorf("DecodeTimeout(%q) returned unexpected error: %v", tc.in, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("DecodeTimeout(%q) = %v; want %v", tc.in, got, tc.want)
+		}
+	}
+
+	invalidCases := []string{
+		"",
+		"S",
+		"+5S",
+		"-5S",
+		"5",
+		"5X",
+		"123456789S", // too many digits
+		"0S",         // not positive
+	}
+	for _, in := range invalidCases {
+		if _, err := grpcweb.DecodeTimeout(in); err == nil {
+			t.Errorf("DecodeTimeout(%q) succeeded; want error", in)
+		}
+	}
+}
+
+// TestHandler_DeadlineExceeded_EndToEnd verifies that a request whose
+// Grpc-Timeout expires before the backend responds gets a trailers-only
+// gRPC-Web response carrying Grpc-Status: 4 (DeadlineExceeded), rather than
+// a raw HTTP error or a hang.
+func TestHandler_DeadlineExceeded_EndToEnd(t *testing.T) {
+	fakeGrpcServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Sleep well past the deadline forwarded onto this request.
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer fakeGrpcServer.Close()
+
+	backendURL, err := url.Parse(fakeGrpcServer.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse backend server URL: %v", err)
+	}
+
+	grpcwebHandler := &grpcweb.Handler{
+		GRPCServer: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			httputil.NewSingleHostReverseProxy(backendURL).ServeHTTP(w, r)
+		}),
+	}
+
+	reqBody, err := createGrpcWebRequestBody([]byte("hello_world"), false)
+	if err != nil {
+		t.Fatalf("Failed to create request body: %v", err)
+	}
+	req := httptest.NewRequest("POST", "/service/method", bytes.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/grpc-web")
+	req.Header.Set("Grpc-Timeout", "50m") // 50 milliseconds
+	rr := httptest.NewRecorder()
+
+	grpcwebHandler.ServeHTTP(rr, req)
+
+	resp := rr.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status OK; got %d", resp.StatusCode)
+	}
+
+	// The only frame should be a trailers-only frame carrying the deadline
+	// status.
+	frameType, data, err := readGrpcWebFrame(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read trailer frame: %v", err)
+	}
+	if frameType != 0x80 {
+		t.Fatalf("Expected a TRAILER frame (0x80), got %x", frameType)
+	}
+
+	trailers := strings.ToLower(strings.ReplaceAll(string(data), " ", ""))
+	if !strings.Contains(trailers, "grpc-status:4") {
+		t.Errorf("Expected trailers to contain 'grpc-status:4'; got %q", trailers)
+	}
+}
+
+// TestEncodeTimeout_UnitSelection verifies that the Grpc-Timeout re-encoded
+// onto the forwarded request picks the smallest unit that still fits in 8
+// digits, observed via the header a fake backend receives. encodeTimeout is
+// unexported, so it's exercised indirectly the same way TestHandler_EndToEnd
+// covers other Handler behavior.
+func TestEncodeTimeout_UnitSelection(t *testing.T) {
+	testCases := []struct {
+		name          string
+		timeoutHeader string
+		wantUnit      byte
+	}{
+		{"NanosecondsWhenTheyFitInEightDigits", "99999999n", 'n'},
+		{"MicrosecondsOnceNanosecondsOverflow", "5S", 'u'},
+		{"MillisecondsOnceMicrosecondsOverflow", "5000S", 'm'},
+		{"SecondsOnceMillisecondsOverflow", "48H", 'S'},
+		{"MinutesOnceSecondsOverflow", "55556H", 'M'},
+		{"HoursOnceMinutesOverflow", "99999999H", 'H'},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotTimeout string
+			fakeGrpcServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotTimeout = r.Header.Get("Grpc-Timeout")
+				w.Header().Set("Content-Type", "application/grpc")
+				w.Header().Add("Trailer", "Grpc-Status")
+				w.Header().Set("Grpc-Status", "0")
+			}))
+			defer fakeGrpcServer.Close()
+
+			backendURL, err := url.Parse(fakeGrpcServer.URL)
+			if err != nil {
+				t.Fatalf("Failed to parse backend server URL: %v", err)
+			}
+
+			grpcwebHandler := &grpcweb.Handler{
+				GRPCServer: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					httputil.NewSingleHostReverseProxy(backendURL).ServeHTTP(w, r)
+				}),
+			}
+
+			reqBody, err := createGrpcWebRequestBody([]byte("hello_world"), false)
+			if err != nil {
+				t.Fatalf("Failed to create request body: %v", err)
+			}
+			req := httptest.NewRequest("POST", "/service/method", bytes.NewReader(reqBody))
+			req.Header.Set("Content-Type", "application/grpc-web")
+			req.Header.Set("Grpc-Timeout", tc.timeoutHeader)
+			rr := httptest.NewRecorder()
+
+			grpcwebHandler.ServeHTTP(rr, req)
+
+			if gotTimeout == "" {
+				t.Fatalf("Backend never saw a Grpc-Timeout header")
+			}
+			if unit := gotTimeout[len(gotTimeout)-1]; unit != tc.wantUnit {
+				t.Errorf("Grpc-Timeout %q re-encoded as %q; want unit %q", tc.timeoutHeader, gotTimeout, string(tc.wantUnit))
+			}
+		})
+	}
+}
+
+// TestHandler_CORSPreflight verifies that Handler answers an OPTIONS
+// preflight request with the headers a gRPC-Web client library needs, and
+// that AllowedOrigins can reject an origin.
+func TestHandler_CORSPreflight(t *testing.T) {
+	h := &grpcweb.Handler{
+		AllowedOrigins: func(origin string) bool { return origin == "https://allowed.example" },
+	}
+
+	t.Run("AllowedOrigin", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodOptions, "/service/method", nil)
+		req.Header.Set("Origin", "https://allowed.example")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		rr := httptest.NewRecorder()
+
+		h.ServeHTTP(rr, req)
+
+		resp := rr.Result()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected status OK; got %d", resp.StatusCode)
+		}
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "https://allowed.example" {
+			t.Errorf("Expected Access-Control-Allow-Origin to be set; got %q", got)
+		}
+		if got := resp.Header.Get("Access-Control-Allow-Headers"); !strings.Contains(got, "Grpc-Timeout") {
+			t.Errorf("Expected Access-Control-Allow-Headers to include Grpc-Timeout; got %q", got)
+		}
+	})
+
+	t.Run("DisallowedOrigin", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodOptions, "/service/method", nil)
+		req.Header.Set("Origin", "https://evil.example")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		rr := httptest.NewRecorder()
+
+		h.ServeHTTP(rr, req)
+
+		if got := rr.Result().Header.Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("Expected no Access-Control-Allow-Origin for a disallowed origin; got %q", got)
+		}
+	})
+}
+
+// TestWriteStatus_StatusDetails round-trips a status.Status carrying an
+// errdetails.BadRequest_FieldViolation through grpcweb.WriteStatus and
+// verifies the Grpc-Status-Details-Bin trailer decodes back to the same
+// details.
+func TestWriteStatus_StatusDetails(t *testing.T) {
+	violation := &errdetails.BadRequest_FieldViolation{
+		Field:       "email",
+		Description: "must not be empty",
+	}
+	st, err := status.New(codes.InvalidArgument, "invalid request").WithDetails(
+		&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{violation}},
+	)
+	if err != nil {
+		t.Fatalf("Failed to attach status details: %v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	if err := grpcweb.WriteStatus(rr, st, false); err != nil {
+		t.Fatalf("WriteStatus returned an error: %v", err)
+	}
+
+	frameType, payload, err := readGrpcWebFrame(rr.Body)
+	if err != nil {
+		t.Fatalf("Failed to read trailer frame: %v", err)
+	}
+	if frameType != 0x80 {
+		t.Fatalf("Expected a TRAILER frame, got %#x", frameType)
+	}
+
+	mimeHeader, err := textproto.NewReader(bufio.NewReader(bytes.NewReader(append(payload, "\r\n"...)))).ReadMIMEHeader()
+	if err != nil {
+		t.Fatalf("Failed to parse trailers: %v", err)
+	}
+	trailers := http.Header(mimeHeader)
+
+	if got := trailers.Get("Grpc-Status"); got != strconv.Itoa(int(codes.InvalidArgument)) {
+		t.Errorf("Expected Grpc-Status %d; got %s", codes.InvalidArgument, got)
+	}
+
+	detailsBin := trailers.Get("Grpc-Status-Details-Bin")
+	if detailsBin == "" {
+		t.Fatalf("Expected a Grpc-Status-Details-Bin trailer")
+	}
+	raw, err := base64.RawStdEncoding.DecodeString(detailsBin)
+	if err != nil {
+		t.Fatalf("Failed to base64-decode Grpc-Status-Details-Bin: %v", err)
+	}
+
+	var decodedStatus spb.Status
+	if err := proto.Unmarshal(raw, &decodedStatus); err != nil {
+		t.Fatalf("Failed to unmarshal google.rpc.Status: %v", err)
+	}
+	if len(decodedStatus.GetDetails()) != 1 {
+		t.Fatalf("Expected exactly one status detail; got %d", len(decodedStatus.GetDetails()))
+	}
+
+	var decodedViolation errdetails.BadRequest
+	if err := decodedStatus.GetDetails()[0].UnmarshalTo(&decodedViolation); err != nil {
+		t.Fatalf("Failed to unmarshal BadRequest detail: %v", err)
+	}
+	if got := decodedViolation.GetFieldViolations()[0].GetField(); got != violation.Field {
+		t.Errorf("Expected field %q; got %q", violation.Field, got)
+	}
+}
+
+// TestFrameReader_CompressedFrame verifies that a gzip-compressed gRPC-Web
+// data frame is decompressed and translated into a gRPC frame carrying the
+// plaintext payload with the compressed flag cleared, so a backend that
+// never negotiated gzip can still read it.
+func TestFrameReader_CompressedFrame(t *testing.T) {
+	plaintext := []byte("hello_world")
+	compressed := gzipCompress(t, plaintext)
+
+	var body bytes.Buffer
+	header := []byte{0x01, 0, 0, 0, 0} // DATA frame, compressed flag set
+	binary.BigEndian.PutUint32(header[1:5], uint32(len(compressed)))
+	body.Write(header)
+	body.Write(compressed)
+
+	reader := grpcweb.NewFrameReader(&body, false, testGzipCompressor{})
+	grpcHeader := make([]byte, 5)
+	if _, err := io.ReadFull(reader, grpcHeader); err != nil {
+		t.Fatalf("Failed to read translated gRPC frame header: %v", err)
+	}
+	if grpcHeader[0] != 0x00 {
+		t.Errorf("Expected gRPC message compressed flag to be cleared, got %#x", grpcHeader[0])
+	}
+
+	length := binary.BigEndian.Uint32(grpcHeader[1:5])
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		t.Fatalf("Failed to read translated gRPC frame payload: %v", err)
+	}
+	if !bytes.Equal(payload, plaintext) {
+		t.Errorf("Expected gRPC frame payload to be decompressed to %q, got %q", plaintext, payload)
+	}
+}
+
+// TestFrameReader_InvalidCompressedFrame verifies that a compressed frame
+// which fails to decompress against the negotiated Grpc-Encoding is rejected
+// rather than forwarded to the gRPC server.
+func TestFrameReader_InvalidCompressedFrame(t *testing.T) {
+	var body bytes.Buffer
+	header := []byte{0x01, 0, 0, 0, 0} // DATA frame, compressed flag set
+	garbage := []byte("not actually gzip data")
+	binary.BigEndian.PutUint32(header[1:5], uint32(len(garbage)))
+	body.Write(header)
+	body.Write(garbage)
+
+	reader := grpcweb.NewFrameReader(&body, false, testGzipCompressor{})
+	if _, err := io.ReadFull(reader, make([]byte, 5)); err == nil {
+		t.Error("Expected reading a frame with an invalid compressed payload to fail, got nil error")
+	}
+}
+
+// TestFrameReader_OversizedLengthWithoutData verifies that a frame header
+// claiming a huge payload, with no payload actually following it, fails
+// cleanly instead of allocating a buffer sized to the claimed length.
+func TestFrameReader_OversizedLengthWithoutData(t *testing.T) {
+	header := []byte{0x00, 0xff, 0xff, 0xff, 0xf0} // DATA frame, length ~4 GiB
+	reader := grpcweb.NewFrameReader(bytes.NewReader(header), false, nil)
+	if _, err := io.ReadFull(reader, make([]byte, 5)); err == nil {
+		t.Error("Expected reading a frame whose declared length exceeds the body sent to fail, got nil error")
+	}
+}
+
+// TestStreamingResponseWriter_DecompressesUnacceptedEncoding verifies that
+// when a backend responds with a Grpc-Encoding the client didn't list in
+// Grpc-Accept-Encoding, the response writer transparently decompresses the
+// frame and advertises Grpc-Encoding: identity instead of forwarding bytes
+// the client can't read.
+func TestStreamingResponseWriter_DecompressesUnacceptedEncoding(t *testing.T) {
+	payload := []byte("hello_world")
+	compressed := gzipCompress(t, payload)
+
+	rec := httptest.NewRecorder()
+	srw := grpcweb.NewStreamingResponseWriter(rec, false, "identity")
+	srw.Header().Set("Grpc-Encoding", "gzip")
+
+	frame := []byte{0x01, 0, 0, 0, 0} // DATA frame, compressed flag set
+	binary.BigEndian.PutUint32(frame[1:5], uint32(len(compressed)))
+	frame = append(frame, compressed...)
+	if _, err := srw.Write(frame); err != nil {
+		t.Fatalf("Failed writing compressed response frame: %v", err)
+	}
+
+	if err := srw.Finish(); err != nil {
+		t.Fatalf("Finish() failed: %v", err)
+	}
+
+	if got := rec.Header().Get("Grpc-Encoding"); got != "identity" {
+		t.Errorf("Grpc-Encoding header = %q; want %q", got, "identity")
+	}
+
+	frameType, got, err := readGrpcWebFrame(rec.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response frame: %v", err)
+	}
+	if frameType&0x01 != 0 {
+		t.Errorf("Expected response frame's compressed flag to be cleared, got %#x", frameType)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Errorf("Response payload = %q; want decompressed %q", got, payload)
+	}
+}
+
+// TestGRPCCodeToHTTPStatus covers the gRPC-to-HTTP status mapping, including
+// the default fallback for codes without an explicit case.
+func TestGRPCCodeToHTTPStatus(t *testing.T) {
+	testCases := []struct {
+		code codes.Code
+		want int
+	}{
+		{codes.OK, http.StatusOK},
+		{codes.Canceled, http.StatusInternalServerError}, // falls through to default
+		{codes.Unknown, http.StatusInternalServerError},
+		{codes.InvalidArgument, http.StatusBadRequest},
+		{codes.DeadlineExceeded, http.StatusGatewayTimeout},
+		{codes.NotFound, http.StatusNotFound},
+		{codes.AlreadyExists, http.StatusConflict},
+		{codes.PermissionDenied, http.StatusForbidden},
+		{codes.ResourceExhausted, http.StatusTooManyRequests},
+		{codes.FailedPrecondition, http.StatusBadRequest},
+		{codes.Aborted, http.StatusConflict},
+		{codes.OutOfRange, http.StatusBadRequest},
+		{codes.Unimplemented, http.StatusNotImplemented},
+		{codes.Internal, http.StatusInternalServerError},
+		{codes.Unavailable, http.StatusServiceUnavailable},
+		{codes.DataLoss, http.StatusInternalServerError},
+		{codes.Unauthenticated, http.StatusUnauthorized},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.code.String(), func(t *testing.T) {
+			if got := grpcweb.GRPCCodeToHTTPStatus(tc.code); got != tc.want {
+				t.Errorf("GRPCCodeToHTTPStatus(%s) = %d; want %d", tc.code, got, tc.want)
+			}
+		})
+	}
+}
+
 // --- Test Helpers ---
 
 // createGrpcWebRequestBody creates a gRPC-Web request body with a single data frame.
@@ -233,3 +841,32 @@ func readGrpcWebFrame(r io.Reader) (frameType byte, payload []byte, err error) {
 	}
 	return frameType, payload, nil
 }
+
+// testGzipCompressor is a grpcweb.Compressor backed by compress/gzip, used
+// to exercise compression-aware code paths without relying on package
+// grpcweb's own internal compressor registry.
+type testGzipCompressor struct{}
+
+func (testGzipCompressor) Name() string { return "gzip" }
+
+func (testGzipCompressor) Compress(w io.Writer) io.WriteCloser {
+	return gzip.NewWriter(w)
+}
+
+func (testGzipCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	return gzip.NewReader(r)
+}
+
+// gzipCompress gzip-compresses payload, failing t on error.
+func gzipCompress(t *testing.T, payload []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	if _, err := gzw.Write(payload); err != nil {
+		t.Fatalf("Failed to gzip payload: %v", err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatalf("Failed to close gzip writer: %v", err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,24 @@
+package grpcweb
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// gzipCompressor implements Compressor using compress/gzip. It is
+// registered automatically under the "gzip" encoding name.
+type gzipCompressor struct{}
+
+func (gzipCompressor) Name() string { return "gzip" }
+
+func (gzipCompressor) Compress(w io.Writer) io.WriteCloser {
+	return gzip.NewWriter(w)
+}
+
+func (gzipCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	return gzip.NewReader(r)
+}
+
+func init() {
+	RegisterCompressor(gzipCompressor{})
+}
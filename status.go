@@ -0,0 +1,77 @@
+package grpcweb
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// WriteStatus writes st to w as a trailers-only gRPC-Web response: no data
+// frame is sent, only a single trailer frame carrying Grpc-Status,
+// Grpc-Message, and, when st carries details, Grpc-Status-Details-Bin.
+func WriteStatus(w http.ResponseWriter, st *status.Status, isText bool) error {
+	srw := NewStreamingResponseWriter(w, isText, "")
+	setStatusTrailers(srw.Trailer(), st)
+	return srw.Finish()
+}
+
+// setStatusTrailers populates trailers with the Grpc-Status, Grpc-Message,
+// and (when present) Grpc-Status-Details-Bin values describing st.
+func setStatusTrailers(trailers http.Header, st *status.Status) {
+	trailers.Set("Grpc-Status", strconv.Itoa(int(st.Code())))
+	trailers.Set("Grpc-Message", percentEncodeStatusMessage(st.Message()))
+
+	proto := st.Proto()
+	if len(proto.GetDetails()) == 0 {
+		return
+	}
+
+	detailsBin, err := marshalStatusDetailsBin(st)
+	if err != nil {
+		return
+	}
+	trailers.Set("Grpc-Status-Details-Bin", detailsBin)
+}
+
+// marshalStatusDetailsBin serializes st's underlying google.rpc.Status proto
+// and returns it as unpadded standard base64, per the gRPC spec's "-bin"
+// trailer convention.
+func marshalStatusDetailsBin(st *status.Status) (string, error) {
+	b, err := proto.Marshal(st.Proto())
+	if err != nil {
+		return "", fmt.Errorf("grpcweb: failed to marshal status details: %w", err)
+	}
+	return base64.RawStdEncoding.EncodeToString(b), nil
+}
+
+// percentEncodeStatusMessage percent-encodes s per the gRPC spec's
+// Status-Message rules: any byte outside the printable ASCII range
+// (0x20-0x7E), as well as '%' itself, is escaped as %XX.
+func percentEncodeStatusMessage(s string) string {
+	needsEncoding := false
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x20 || c > 0x7E || c == '%' {
+			needsEncoding = true
+			break
+		}
+	}
+	if !needsEncoding {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x20 || c > 0x7E || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,70 @@
+package grpcweb
+
+import (
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/stats"
+)
+
+// Option configures a Handler constructed by WrapGRPCServer.
+type Option func(*wrapOptions)
+
+type wrapOptions struct {
+	maxMessageSize int
+	statsHandlers  []stats.Handler
+}
+
+// WithMaxMessageSize bounds the size, in bytes, of a request body
+// WrapGRPCServer will read before aborting the RPC. A size of 0, the
+// default, leaves the body unbounded.
+func WithMaxMessageSize(size int) Option {
+	return func(o *wrapOptions) {
+		o.maxMessageSize = size
+	}
+}
+
+// WithStatsHandler registers a stats.Handler to observe RPCs proxied by
+// WrapGRPCServer, independent of any stats.Handler already configured on
+// the underlying *grpc.Server.
+func WithStatsHandler(h stats.Handler) Option {
+	return func(o *wrapOptions) {
+		o.statsHandlers = append(o.statsHandlers, h)
+	}
+}
+
+// WrapGRPCServer returns an http.Handler that serves gRPC-Web requests by
+// invoking s.ServeHTTP directly, without reverse-proxying to a separate
+// HTTP/2 listener. This lets streaming RPCs work without a loopback hop,
+// and lets callers register gRPC services behind a single gRPC-Web handler.
+func WrapGRPCServer(s *grpc.Server, opts ...Option) http.Handler {
+	var o wrapOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Handler{
+		GRPCServer: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			for _, h := range o.statsHandlers {
+				h := h
+				ctx = h.TagRPC(ctx, &stats.RPCTagInfo{FullMethodName: r.URL.Path})
+				h.HandleRPC(ctx, &stats.Begin{})
+				defer h.HandleRPC(ctx, &stats.End{})
+			}
+
+			// grpc.Server.ServeHTTP requires an HTTP/2 request and a
+			// ResponseWriter that supports http.Flusher; StreamingResponseWriter
+			// (constructed by Handler.ServeHTTP) already satisfies the latter,
+			// so only the request's advertised protocol needs spoofing.
+			r = r.Clone(ctx)
+			r.ProtoMajor, r.ProtoMinor = 2, 0
+
+			if o.maxMessageSize > 0 {
+				r.Body = http.MaxBytesReader(w, r.Body, int64(o.maxMessageSize))
+			}
+
+			s.ServeHTTP(w, r)
+		}),
+	}
+}